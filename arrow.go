@@ -0,0 +1,529 @@
+package datareader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+	"github.com/apache/arrow/go/v14/arrow/ipc"
+	"github.com/apache/arrow/go/v14/arrow/memory"
+	"github.com/apache/arrow/go/v14/parquet"
+	"github.com/apache/arrow/go/v14/parquet/compress"
+	"github.com/apache/arrow/go/v14/parquet/file"
+	"github.com/apache/arrow/go/v14/parquet/pqarrow"
+)
+
+// categoricalCodesMetadataKey names the Arrow field metadata entry
+// that records, in dictionary-position order, the original integer
+// codes a categorical Series' dictionary indices stand for.  Without
+// it, a dictionary-encoded column can only recover the *position* a
+// code was written at, not the code itself.
+const categoricalCodesMetadataKey = "categoricalCodes"
+
+// ParquetOptions control how SeriesArray.WriteParquet lays out the
+// file it produces.
+type ParquetOptions struct {
+
+	// ChunkSize is the number of rows per row group.  If zero, the
+	// whole SeriesArray is written as a single row group.
+	ChunkSize int64
+
+	// Compression is the codec applied to each column chunk.  If
+	// unset, compress.Codecs.Snappy is used.  compress.Codecs.Uncompressed
+	// is the zero value, so it also selects Snappy.
+	Compression compress.Compression
+}
+
+// arrowType returns the Arrow data type that corresponds to the
+// concrete type held by a Series' data field.  A categorical Series
+// (see Series.IsCategorical) is mapped to a dictionary-encoded type,
+// with Int32 dictionary positions as the index type (see
+// categoricalColumn) and labels as UTF-8 values.
+func arrowType(s *Series) (arrow.DataType, error) {
+
+	if s.IsCategorical() {
+		return &arrow.DictionaryType{
+			IndexType: arrow.PrimitiveTypes.Int32,
+			ValueType: arrow.BinaryTypes.String,
+		}, nil
+	}
+
+	data := s.data
+	switch data.(type) {
+	default:
+		return nil, fmt.Errorf("Series type %T has no Arrow equivalent", data)
+	case []float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case []float32:
+		return arrow.PrimitiveTypes.Float32, nil
+	case []int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case []int32:
+		return arrow.PrimitiveTypes.Int32, nil
+	case []int16:
+		return arrow.PrimitiveTypes.Int16, nil
+	case []int8:
+		return arrow.PrimitiveTypes.Int8, nil
+	case []uint64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case []string:
+		return arrow.BinaryTypes.String, nil
+	case []time.Time:
+		return arrow.FixedWidthTypes.Timestamp_us, nil
+	}
+}
+
+// arrowSchema builds the Arrow schema for a SeriesArray, in column
+// order.  A categorical column's field carries its dictionary's
+// original codes, in position order, under categoricalCodesMetadataKey
+// (see categoricalCodes), so they survive round through a compact
+// dictionary index.
+func (ser SeriesArray) arrowSchema() (*arrow.Schema, error) {
+
+	fields := make([]arrow.Field, len(ser))
+	for j, s := range ser {
+		ty, err := arrowType(s)
+		if err != nil {
+			return nil, err
+		}
+		field := arrow.Field{Name: s.Name, Type: ty, Nullable: true}
+
+		if s.IsCategorical() {
+			codes, err := categoricalCodes(s)
+			if err != nil {
+				return nil, err
+			}
+			field.Metadata = arrow.NewMetadata(
+				[]string{categoricalCodesMetadataKey},
+				[]string{encodeCategoricalCodes(codes)},
+			)
+		}
+
+		fields[j] = field
+	}
+
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// categoricalCodes returns the sorted, deduplicated set of non-negative
+// codes a categorical Series can take: every key of its label
+// dictionary, plus every non-missing code actually present in its
+// data.  This is the *domain* of codes, not the full numeric range
+// between them, so a dictionary built from it stays small regardless
+// of how large an individual code is (e.g. a SAS/Stata sentinel
+// "special missing" code).  Negative codes are rejected, since they
+// cannot be represented in a positional dictionary index.
+func categoricalCodes(s *Series) ([]int64, error) {
+
+	codes, ok := s.data.([]int64)
+	if !ok {
+		return nil, fmt.Errorf("categoricalCodes: categorical Series %s does not hold []int64 codes", s.Name)
+	}
+
+	seen := make(map[int64]bool, len(s.Categories()))
+	for c := range s.Categories() {
+		if c < 0 {
+			return nil, fmt.Errorf("categoricalCodes: negative category code %d is not supported", c)
+		}
+		seen[c] = true
+	}
+	for i, c := range codes {
+		if s.missing != nil && s.missing[i] {
+			continue
+		}
+		if c < 0 {
+			return nil, fmt.Errorf("categoricalCodes: negative code %d is not supported", c)
+		}
+		seen[c] = true
+	}
+
+	distinct := make([]int64, 0, len(seen))
+	for c := range seen {
+		distinct = append(distinct, c)
+	}
+	sort.Slice(distinct, func(i, j int) bool { return distinct[i] < distinct[j] })
+
+	return distinct, nil
+}
+
+// encodeCategoricalCodes renders codes as the comma-separated string
+// stored in categoricalCodesMetadataKey.
+func encodeCategoricalCodes(codes []int64) string {
+	parts := make([]string, len(codes))
+	for i, c := range codes {
+		parts[i] = strconv.FormatInt(c, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeCategoricalCodes parses the string produced by
+// encodeCategoricalCodes.  An empty string decodes to nil, the case
+// for a categorical Series with no codes at all.
+func decodeCategoricalCodes(s string) ([]int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	codes := make([]int64, len(parts))
+	for i, p := range parts {
+		c, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("decodeCategoricalCodes: %w", err)
+		}
+		codes[i] = c
+	}
+	return codes, nil
+}
+
+// appendColumn appends the data and missingness of a Series to an
+// Arrow array builder.  Categorical series are not handled here; see
+// categoricalColumn.
+func appendColumn(bld array.Builder, s *Series) error {
+
+	isMissing := func(i int) bool {
+		return s.missing != nil && s.missing[i]
+	}
+
+	switch data := s.data.(type) {
+	default:
+		return fmt.Errorf("Series type %T has no Arrow equivalent", s.data)
+	case []float64:
+		b := bld.(*array.Float64Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []float32:
+		b := bld.(*array.Float32Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []int64:
+		b := bld.(*array.Int64Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []int32:
+		b := bld.(*array.Int32Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []int16:
+		b := bld.(*array.Int16Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []int8:
+		b := bld.(*array.Int8Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []uint64:
+		b := bld.(*array.Uint64Builder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []string:
+		b := bld.(*array.StringBuilder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(v)
+			}
+		}
+	case []time.Time:
+		b := bld.(*array.TimestampBuilder)
+		for i, v := range data {
+			if isMissing(i) {
+				b.AppendNull()
+			} else {
+				b.Append(arrow.Timestamp(v.UnixMicro()))
+			}
+		}
+	}
+
+	return nil
+}
+
+// categoricalColumn builds an Arrow dictionary-encoded array for a
+// categorical Series.  The dictionary's values are built from the
+// distinct codes recorded in field's categoricalCodesMetadataKey
+// metadata (see categoricalCodes), in position order, and the series'
+// codes are translated into positions in that dictionary.  This keeps
+// the dictionary's size proportional to the number of distinct
+// categories rather than to the numeric span of the codes, while the
+// metadata lets seriesArrayFromRecord translate positions back to the
+// original codes so Codes() survives a WriteArrow/ReadArrow round
+// trip unchanged.
+func categoricalColumn(pool memory.Allocator, s *Series, field arrow.Field) (arrow.Array, error) {
+
+	codes := s.data.([]int64)
+	categories := s.Categories()
+
+	metaIdx := field.Metadata.FindKey(categoricalCodesMetadataKey)
+	if metaIdx < 0 {
+		return nil, fmt.Errorf("categoricalColumn: field %q is missing its %s metadata", field.Name, categoricalCodesMetadataKey)
+	}
+	distinct, err := decodeCategoricalCodes(field.Metadata.Values()[metaIdx])
+	if err != nil {
+		return nil, err
+	}
+
+	pos := make(map[int64]int32, len(distinct))
+	valBld := array.NewStringBuilder(pool)
+	defer valBld.Release()
+	for i, c := range distinct {
+		pos[c] = int32(i)
+		lab, ok := categories[c]
+		if !ok {
+			lab = fmt.Sprintf("%d", c)
+		}
+		valBld.Append(lab)
+	}
+	values := valBld.NewStringArray()
+	defer values.Release()
+
+	idxBld := array.NewInt32Builder(pool)
+	defer idxBld.Release()
+	for i, c := range codes {
+		if s.missing != nil && s.missing[i] {
+			idxBld.AppendNull()
+			continue
+		}
+		p, ok := pos[c]
+		if !ok {
+			return nil, fmt.Errorf("categoricalColumn: code %d is not in the column's recorded code set", c)
+		}
+		idxBld.Append(p)
+	}
+	indices := idxBld.NewInt32Array()
+	defer indices.Release()
+
+	return array.NewDictionaryArray(&arrow.DictionaryType{
+		IndexType: arrow.PrimitiveTypes.Int32,
+		ValueType: arrow.BinaryTypes.String,
+	}, indices, values), nil
+}
+
+// record builds a single Arrow record batch holding the rows
+// [first, last) of ser.
+func (ser SeriesArray) record(schema *arrow.Schema, pool memory.Allocator, first, last int) (arrow.Record, error) {
+
+	cols := make([]arrow.Array, len(ser))
+	for j, s := range ser {
+		sub, err := NewSeries(s.Name, sliceData(s.data, first, last), sliceMissing(s.missing, first, last))
+		if err != nil {
+			return nil, err
+		}
+
+		if s.IsCategorical() {
+			sub.SetCategories(s.Categories())
+			col, err := categoricalColumn(pool, sub, schema.Field(j))
+			if err != nil {
+				return nil, err
+			}
+			cols[j] = col
+			continue
+		}
+
+		bld := array.NewBuilder(pool, schema.Field(j).Type)
+		defer bld.Release()
+		if err := appendColumn(bld, sub); err != nil {
+			return nil, err
+		}
+		cols[j] = bld.NewArray()
+	}
+
+	return array.NewRecord(schema, cols, int64(last-first)), nil
+}
+
+// WriteArrow writes ser to w in the Arrow IPC stream format.  chunkRows
+// controls how many rows are placed in each record batch; if zero,
+// the whole SeriesArray is written as a single record batch.
+func (ser SeriesArray) WriteArrow(w io.Writer, chunkRows int) error {
+
+	if len(ser) == 0 {
+		return fmt.Errorf("WriteArrow: empty SeriesArray")
+	}
+
+	schema, err := ser.arrowSchema()
+	if err != nil {
+		return err
+	}
+
+	if chunkRows <= 0 {
+		chunkRows = ser[0].length
+	}
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(schema))
+	defer writer.Close()
+
+	pool := memory.NewGoAllocator()
+	for first := 0; first < ser[0].length; first += chunkRows {
+		last := first + chunkRows
+		if last > ser[0].length {
+			last = ser[0].length
+		}
+		rec, err := ser.record(schema, pool, first, last)
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(rec); err != nil {
+			rec.Release()
+			return err
+		}
+		rec.Release()
+	}
+
+	return nil
+}
+
+// WriteParquet writes ser to w in Parquet format, using opts to
+// control row group size and compression.
+func (ser SeriesArray) WriteParquet(w io.Writer, opts ParquetOptions) error {
+
+	if len(ser) == 0 {
+		return fmt.Errorf("WriteParquet: empty SeriesArray")
+	}
+
+	schema, err := ser.arrowSchema()
+	if err != nil {
+		return err
+	}
+
+	chunkRows := opts.ChunkSize
+	if chunkRows <= 0 {
+		chunkRows = int64(ser[0].length)
+	}
+
+	compression := opts.Compression
+	if compression == 0 {
+		compression = compress.Codecs.Snappy
+	}
+
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(compression),
+		parquet.WithMaxRowGroupLength(chunkRows),
+	)
+	// WithStoreSchema embeds the full Arrow schema, including the
+	// per-field metadata categoricalColumn relies on, so a categorical
+	// column's codes survive a WriteParquet/ReadParquet round trip too.
+	writer, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	pool := memory.NewGoAllocator()
+	for first := 0; first < ser[0].length; first += int(chunkRows) {
+		last := first + int(chunkRows)
+		if last > ser[0].length {
+			last = ser[0].length
+		}
+		rec, err := ser.record(schema, pool, first, last)
+		if err != nil {
+			return err
+		}
+		err = writer.WriteBuffered(rec)
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadArrow reads a SeriesArray back from an Arrow IPC stream
+// previously written by WriteArrow.
+func ReadArrow(r io.Reader) (SeriesArray, error) {
+
+	reader, err := ipc.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Release()
+
+	var out SeriesArray
+	for reader.Next() {
+		rec := reader.Record()
+		chunk, err := seriesArrayFromRecord(rec)
+		if err != nil {
+			return nil, err
+		}
+		out = appendSeriesArray(out, chunk)
+	}
+
+	return out, reader.Err()
+}
+
+// ReadParquet reads a SeriesArray back from a Parquet file previously
+// written by WriteParquet.
+func ReadParquet(r parquet.ReaderAtSeeker) (SeriesArray, error) {
+
+	pf, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.NewGoAllocator())
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err := fr.ReadTable(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tbl.Release()
+
+	tr := array.NewTableReader(tbl, tbl.NumRows())
+	defer tr.Release()
+
+	var out SeriesArray
+	for tr.Next() {
+		chunk, err := seriesArrayFromRecord(tr.Record())
+		if err != nil {
+			return nil, err
+		}
+		out = appendSeriesArray(out, chunk)
+	}
+
+	return out, nil
+}
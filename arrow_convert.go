@@ -0,0 +1,276 @@
+package datareader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v14/arrow"
+	"github.com/apache/arrow/go/v14/arrow/array"
+)
+
+// sliceData returns the subinterval [first, last) of a Series' data
+// field, preserving its concrete type.
+func sliceData(data interface{}, first, last int) interface{} {
+	switch x := data.(type) {
+	default:
+		panic(fmt.Sprintf("sliceData: unknown type %T", data))
+	case []float64:
+		return x[first:last]
+	case []float32:
+		return x[first:last]
+	case []int64:
+		return x[first:last]
+	case []int32:
+		return x[first:last]
+	case []int16:
+		return x[first:last]
+	case []int8:
+		return x[first:last]
+	case []uint64:
+		return x[first:last]
+	case []string:
+		return x[first:last]
+	case []time.Time:
+		return x[first:last]
+	}
+}
+
+// sliceMissing returns the subinterval [first, last) of a missingness
+// mask, or nil if the mask is nil.
+func sliceMissing(missing []bool, first, last int) []bool {
+	if missing == nil {
+		return nil
+	}
+	return missing[first:last]
+}
+
+// seriesArrayFromRecord converts a single Arrow record batch into a
+// SeriesArray, inverting appendColumn.
+func seriesArrayFromRecord(rec arrow.Record) (SeriesArray, error) {
+
+	schema := rec.Schema()
+	out := make(SeriesArray, rec.NumCols())
+
+	for j := 0; j < int(rec.NumCols()); j++ {
+		col := rec.Column(j)
+		n := col.Len()
+		missing := make([]bool, n)
+		anyMissing := false
+
+		var data interface{}
+		var labels map[int64]string
+		switch c := col.(type) {
+		default:
+			return nil, fmt.Errorf("seriesArrayFromRecord: unsupported Arrow column type %T", col)
+		case *array.Dictionary:
+			values, ok := c.Dictionary().(*array.String)
+			if !ok {
+				return nil, fmt.Errorf("seriesArrayFromRecord: unsupported dictionary value type %T", c.Dictionary())
+			}
+
+			// The dictionary index is a position into values, not the
+			// original code (see categoricalColumn); recover the code
+			// each position stands for from the field's metadata.
+			metaIdx := schema.Field(j).Metadata.FindKey(categoricalCodesMetadataKey)
+			if metaIdx < 0 {
+				return nil, fmt.Errorf("seriesArrayFromRecord: column %q is missing its %s metadata", schema.Field(j).Name, categoricalCodesMetadataKey)
+			}
+			positionCodes, err := decodeCategoricalCodes(schema.Field(j).Metadata.Values()[metaIdx])
+			if err != nil {
+				return nil, err
+			}
+
+			codes := make([]int64, n)
+			labels = make(map[int64]string)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+					continue
+				}
+				pos := c.GetValueIndex(i)
+				if pos < 0 || pos >= len(positionCodes) {
+					return nil, fmt.Errorf("seriesArrayFromRecord: dictionary position %d out of range for column %q", pos, schema.Field(j).Name)
+				}
+				code := positionCodes[pos]
+				codes[i] = code
+				labels[code] = values.Value(pos)
+			}
+			data = codes
+		case *array.Float64:
+			d := make([]float64, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Float32:
+			d := make([]float32, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Int64:
+			d := make([]int64, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Int32:
+			d := make([]int32, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Int16:
+			d := make([]int16, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Int8:
+			d := make([]int8, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Uint64:
+			d := make([]uint64, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.String:
+			d := make([]string, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i)
+				}
+			}
+			data = d
+		case *array.Timestamp:
+			d := make([]time.Time, n)
+			for i := 0; i < n; i++ {
+				if c.IsNull(i) {
+					missing[i], anyMissing = true, true
+				} else {
+					d[i] = c.Value(i).ToTime(arrow.Microsecond)
+				}
+			}
+			data = d
+		}
+
+		if !anyMissing {
+			missing = nil
+		}
+
+		s, err := NewSeries(schema.Field(j).Name, data, missing)
+		if err != nil {
+			return nil, err
+		}
+		if labels != nil {
+			s.SetCategories(labels)
+		}
+		out[j] = s
+	}
+
+	return out, nil
+}
+
+// appendSeriesArray concatenates src onto dst column-by-column,
+// allocating dst on the first call.
+func appendSeriesArray(dst, src SeriesArray) SeriesArray {
+
+	if dst == nil {
+		return src
+	}
+
+	for j := range dst {
+		dst[j] = concatSeries(dst[j], src[j])
+	}
+
+	return dst
+}
+
+// concatSeries returns a new Series holding the rows of a followed by
+// the rows of b.  a and b must hold the same concrete data type.
+func concatSeries(a, b *Series) *Series {
+
+	var missing []bool
+	if a.missing != nil || b.missing != nil {
+		missing = make([]bool, a.length+b.length)
+		if a.missing != nil {
+			copy(missing, a.missing)
+		}
+		if b.missing != nil {
+			copy(missing[a.length:], b.missing)
+		}
+	}
+
+	var data interface{}
+	switch x := a.data.(type) {
+	default:
+		panic(fmt.Sprintf("concatSeries: unknown type %T", a.data))
+	case []float64:
+		data = append(append([]float64{}, x...), b.data.([]float64)...)
+	case []float32:
+		data = append(append([]float32{}, x...), b.data.([]float32)...)
+	case []int64:
+		data = append(append([]int64{}, x...), b.data.([]int64)...)
+	case []int32:
+		data = append(append([]int32{}, x...), b.data.([]int32)...)
+	case []int16:
+		data = append(append([]int16{}, x...), b.data.([]int16)...)
+	case []int8:
+		data = append(append([]int8{}, x...), b.data.([]int8)...)
+	case []uint64:
+		data = append(append([]uint64{}, x...), b.data.([]uint64)...)
+	case []string:
+		data = append(append([]string{}, x...), b.data.([]string)...)
+	case []time.Time:
+		data = append(append([]time.Time{}, x...), b.data.([]time.Time)...)
+	}
+
+	s, _ := NewSeries(a.Name, data, missing)
+
+	if a.IsCategorical() || b.IsCategorical() {
+		labels := make(map[int64]string)
+		for c, lab := range a.Categories() {
+			labels[c] = lab
+		}
+		for c, lab := range b.Categories() {
+			labels[c] = lab
+		}
+		s.SetCategories(labels)
+	}
+
+	return s
+}
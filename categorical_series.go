@@ -0,0 +1,57 @@
+package datareader
+
+// CategoricalSeries is a Series variant for categorical (factor) data,
+// as produced by SAS and Stata value labels.  It is a thin wrapper
+// around a *Series whose data is []int64 codes and whose Categories
+// dictionary maps each code to its label, so a CategoricalSeries can
+// be stored in a SeriesArray ([]*Series) like any other column while
+// still exposing a code/label-oriented API.  Comparisons are done on
+// the codes, not the label strings, so otherwise-identical data with
+// differently-ordered label dictionaries still compares equal.
+type CategoricalSeries struct {
+	*Series
+}
+
+// NewCategoricalSeries returns a new CategoricalSeries with the given
+// name, integer codes, missingness mask, and code-to-label
+// dictionary.
+func NewCategoricalSeries(name string, codes []int64, missing []bool, labels map[int64]string) (*CategoricalSeries, error) {
+
+	ser, err := NewSeries(name, codes, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	if labels == nil {
+		labels = make(map[int64]string)
+	}
+	ser.SetCategories(labels)
+
+	return &CategoricalSeries{Series: ser}, nil
+}
+
+// Codes returns the integer codes underlying the CategoricalSeries.
+func (cs *CategoricalSeries) Codes() []int64 {
+	return cs.data.([]int64)
+}
+
+// Labels returns the labeled string value for every element, in
+// order.  A code with no entry in the dictionary is rendered as its
+// numeric value.  A missing element is rendered as "".
+func (cs *CategoricalSeries) Labels() []string {
+	return cs.ToString().Data().([]string)
+}
+
+// AllClose compares the codes (not the label strings) of two
+// CategoricalSeries, so that otherwise-identical data with
+// differently-ordered label dictionaries still compares equal.  tol
+// is accepted for symmetry with Series.AllClose but is ignored, since
+// codes are always compared exactly.
+func (cs *CategoricalSeries) AllClose(other *CategoricalSeries, tol float64) (bool, int) {
+	return cs.Series.AllClose(other.Series, 0.0)
+}
+
+// AllEqual is equivalent to AllClose with tol=0.
+func (cs *CategoricalSeries) AllEqual(other *CategoricalSeries) (bool, int) {
+	return cs.AllClose(other, 0.0)
+}
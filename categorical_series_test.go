@@ -0,0 +1,53 @@
+package datareader
+
+import "testing"
+
+func TestCategoricalSeriesAllCloseIgnoresLabelOrdering(t *testing.T) {
+
+	a, err := NewCategoricalSeries("grp", []int64{0, 1, 2, 1}, nil, map[int64]string{
+		0: "low", 1: "medium", 2: "high",
+	})
+	if err != nil {
+		t.Fatalf("NewCategoricalSeries: %v", err)
+	}
+
+	b, err := NewCategoricalSeries("grp", []int64{0, 1, 2, 1}, nil, map[int64]string{
+		2: "high", 0: "low", 1: "medium",
+	})
+	if err != nil {
+		t.Fatalf("NewCategoricalSeries: %v", err)
+	}
+
+	if ok, i := a.AllEqual(b); !ok {
+		t.Fatalf("expected equal codes regardless of label insertion order, first mismatch at %d", i)
+	}
+
+	c, err := NewCategoricalSeries("grp", []int64{0, 1, 2, 0}, nil, map[int64]string{
+		0: "low", 1: "medium", 2: "high",
+	})
+	if err != nil {
+		t.Fatalf("NewCategoricalSeries: %v", err)
+	}
+
+	if ok, i := a.AllEqual(c); ok || i != 3 {
+		t.Fatalf("expected mismatch at index 3, got ok=%v i=%d", ok, i)
+	}
+}
+
+func TestCategoricalSeriesToString(t *testing.T) {
+
+	cs, err := NewCategoricalSeries("grp", []int64{0, 1, 99}, nil, map[int64]string{
+		0: "low", 1: "high",
+	})
+	if err != nil {
+		t.Fatalf("NewCategoricalSeries: %v", err)
+	}
+
+	got := cs.ToString().Data().([]string)
+	want := []string{"low", "high", "99"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("index %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -3,7 +3,6 @@ package datareader
 import (
 	"fmt"
 	"io"
-	"math"
 	"os"
 	"strconv"
 	"time"
@@ -11,6 +10,19 @@ import (
 
 // A Series is a homogeneously-typed one-dimensional sequence of data
 // values, with an optional mask for missing values.
+//
+// Series cannot literally embed a TypedSeries[T], since T is a
+// compile-time type parameter and Series must go on accepting
+// arbitrary element types chosen at run time (e.g. by the SAS and
+// Stata readers, which don't know a column's type until they've read
+// its header). Instead, every Series method that used to carry a
+// full per-type loop now switches on the concrete element type once
+// and delegates the body of each arm to a generic algorithm in
+// typed_series.go (allCloseRange, writeRange, upcastRange,
+// toStringRange; TypedSeries.AllClose shares allCloseRange). That is
+// as close to "thin wrapper around the generic form" as a
+// runtime-typed Series can get without giving up its interface{}
+// storage.
 type Series struct {
 
 	// A name describing what is in this series.
@@ -25,6 +37,11 @@ type Series struct {
 	// Indicators that data values are missing.  If nil, there are
 	// no missing values.
 	missing []bool
+
+	// labels maps the integer codes in an []int64 data series to
+	// their category labels.  If nil, the series is not
+	// categorical.  See CategoricalSeries.
+	labels map[int64]string
 }
 
 // NewSeries returns a new Series object with the given name and data
@@ -78,90 +95,27 @@ func (ser *Series) WriteRange(w io.Writer, first, last int) {
 	ty := fmt.Sprintf("%T", ser.data)
 	io.WriteString(w, fmt.Sprintf("Type: %s\n", ty[2:len(ty)]))
 
-	switch ser.data.(type) {
+	switch data := ser.data.(type) {
 	default:
 		panic("Unknown type in WriteRange")
 	case []float64:
-		data := ser.data.([]float64)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []float32:
-		data := ser.data.([]float32)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []int64:
-		data := ser.data.([]int64)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []int32:
-		data := ser.data.([]int32)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []int16:
-		data := ser.data.([]int16)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []int8:
-		data := ser.data.([]int8)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []uint64:
-		data := ser.data.([]uint64)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []string:
-		data := ser.data.([]string)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	case []time.Time:
-		data := ser.data.([]time.Time)
-		for j := first; j < last; j++ {
-			if (ser.missing == nil) || !ser.missing[j] {
-				io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
-			} else {
-				io.WriteString(w, fmt.Sprintf("%d:\n", j))
-			}
-		}
+		writeRange(w, data, ser.missing, first, last)
 	}
 }
 
@@ -190,6 +144,24 @@ func (ser *Series) Length() int {
 	return ser.length
 }
 
+// IsCategorical returns true if ser holds integer codes with an
+// associated category label dictionary, as set by SetCategories.
+func (ser *Series) IsCategorical() bool {
+	return ser.labels != nil
+}
+
+// Categories returns the code-to-label dictionary set by
+// SetCategories, or nil if ser is not categorical.
+func (ser *Series) Categories() map[int64]string {
+	return ser.labels
+}
+
+// SetCategories marks ser as categorical, with codes (which must be
+// an []int64) mapped to labels through the given dictionary.
+func (ser *Series) SetCategories(labels map[int64]string) {
+	ser.labels = labels
+}
+
 // AllClose returns true, 0 if the Series is within tol of the other
 // series.  If the Series have different lengths, AllClose returns
 // false, -1.  If the Series have different types, AllClose returns
@@ -202,142 +174,7 @@ func (ser *Series) AllClose(other *Series, tol float64) (bool, int) {
 		return false, -1
 	}
 
-	if (ser.missing != nil) && (other.missing != nil) {
-		for j := 0; j < ser.length; j++ {
-			if ser.missing[j] != other.missing[j] {
-				return false, j
-			}
-		}
-	}
-
-	// Utility function for missing mask
-	cmiss := func(j int) int {
-		f1 := (ser.missing == nil) || (ser.missing[j] == false)
-		f2 := (other.missing == nil) || (other.missing[j] == false)
-		if f1 != f2 {
-			return 0 // inconsistent
-		} else if f1 {
-			return 1 // both non-missing
-		} else {
-			return 2 // both missing
-		}
-	}
-
-	switch ser.data.(type) {
-	default:
-		panic(fmt.Sprintf("Unknown type %T in Series.AllClose", ser.data))
-	case []float64:
-		u := ser.data.([]float64)
-		v, ok := other.data.([]float64)
-		if !ok {
-			return false, -2
-		}
-		for i := 0; i < ser.length; i++ {
-			c := cmiss(i)
-			if c == 0 {
-				return false, i
-			}
-			if (c == 1) && (math.Abs(u[i]-v[i]) > tol) {
-				return false, i
-			}
-		}
-	case []float32:
-		u := ser.data.([]float32)
-		v, ok := other.data.([]float32)
-		if !ok {
-			return false, -2
-		}
-		for i := 0; i < ser.length; i++ {
-			c := cmiss(i)
-			if c == 0 {
-				return false, i
-			}
-			if (c == 1) && (math.Abs(float64(u[i]-v[i])) > tol) {
-				return false, i
-			}
-		}
-	case []int64:
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (ser.data.([]int64)[j] != other.data.([]int64)[j]) {
-				return false, j
-			}
-		}
-	case []int32:
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (ser.data.([]int32)[j] != other.data.([]int32)[j]) {
-				return false, j
-			}
-		}
-	case []int16:
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (ser.data.([]int16)[j] != other.data.([]int16)[j]) {
-				return false, j
-			}
-		}
-	case []int8:
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (ser.data.([]int8)[j] != other.data.([]int8)[j]) {
-				return false, j
-			}
-		}
-	case []uint64:
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (ser.data.([]uint64)[j] != other.data.([]uint64)[j]) {
-				return false, j
-			}
-		}
-	case []string:
-		u := ser.data.([]string)
-		v, ok := other.data.([]string)
-		if !ok {
-			return false, -2
-		}
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && (u[j] != v[j]) {
-				return false, j
-			}
-		}
-	case []time.Time:
-		u := ser.data.([]time.Time)
-		v, ok := other.data.([]time.Time)
-		if !ok {
-			return false, -2
-		}
-		for j := 0; j < ser.length; j++ {
-			c := cmiss(j)
-			if c == 0 {
-				return false, j
-			}
-			if (c == 1) && !u[j].Equal(v[j]) {
-				return false, j
-			}
-		}
-	}
-	return true, 0
+	return ser.AllCloseInto(other, tol, 0, ser.length)
 }
 
 // AllEqual is equivalent to AllClose with tol=0.
@@ -356,8 +193,7 @@ func (ser *Series) UpcastNumeric() *Series {
 		copy(cmiss, ser.missing)
 	}
 
-	switch ser.data.(type) {
-
+	switch d := ser.data.(type) {
 	default:
 		panic(fmt.Sprintf("unknown data type: %T\n", ser.data))
 	case []float64:
@@ -367,51 +203,33 @@ func (ser *Series) UpcastNumeric() *Series {
 	case []time.Time:
 		return ser
 	case []float32:
-		d := ser.data.([]float32)
-		n := len(d)
-		a := make([]float64, n)
-		for i := 0; i < n; i++ {
-			a[i] = float64(d[i])
-		}
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
 		s, _ := NewSeries(ser.Name, a, cmiss)
 		return s
 	case []int64:
-		d := ser.data.([]int64)
-		n := len(d)
-		a := make([]float64, n)
-		for i := 0; i < n; i++ {
-			a[i] = float64(d[i])
-		}
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
 		s, _ := NewSeries(ser.Name, a, cmiss)
 		return s
 	case []int32:
-		d := ser.data.([]int32)
-		n := len(d)
-		a := make([]float64, n)
-		for i := 0; i < n; i++ {
-			a[i] = float64(d[i])
-		}
-		ser.data = a
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
 		s, _ := NewSeries(ser.Name, a, cmiss)
 		return s
 	case []int16:
-		d := ser.data.([]int16)
-		n := len(d)
-		a := make([]float64, n)
-		for i := 0; i < n; i++ {
-			a[i] = float64(d[i])
-		}
-		ser.data = a
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
 		s, _ := NewSeries(ser.Name, a, cmiss)
 		return s
 	case []int8:
-		d := ser.data.([]int8)
-		n := len(d)
-		a := make([]float64, n)
-		for i := 0; i < n; i++ {
-			a[i] = float64(d[i])
-		}
-		ser.data = a
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
+		s, _ := NewSeries(ser.Name, a, cmiss)
+		return s
+	case []uint64:
+		a := make([]float64, len(d))
+		upcastRange(a, d, 0, len(d))
 		s, _ := NewSeries(ser.Name, a, cmiss)
 		return s
 	}
@@ -491,19 +309,30 @@ func (ser *Series) ToString() *Series {
 		copy(cmiss, ser.missing)
 	}
 
-	switch ser.data.(type) {
+	if ser.labels != nil {
+		codes := ser.data.([]int64)
+		x := make([]string, n)
+		for i, c := range codes {
+			if cmiss[i] {
+				continue
+			}
+			if lab, ok := ser.labels[c]; ok {
+				x[i] = lab
+			} else {
+				x[i] = fmt.Sprintf("%d", c)
+			}
+		}
+		s, _ := NewSeries(ser.Name, x, cmiss)
+		return s
+	}
+
+	switch d := ser.data.(type) {
 	default:
 		panic(fmt.Sprintf("unknown data type %T in ToString", ser.data))
 	case []string:
 		return ser
 	case []float64:
-		x := make([]string, n)
-		y := ser.data.([]float64)
-		for i := 0; i < n; i++ {
-			if !cmiss[i] {
-				x[i] = fmt.Sprintf("%v", y[i])
-			}
-		}
+		x := toStringRange(d, ser.missing, 0, n)
 		s, _ := NewSeries(ser.Name, x, cmiss)
 		return s
 	}
@@ -579,9 +408,9 @@ func (ser *Series) Date_from_duration(base time.Time, units string) (*Series, er
 		copy(miss, ser.missing)
 	}
 
-	td, err := upcast_numeric(ser.data)
-	if err != nil {
-		return nil, err
+	td, ok := ser.UpcastNumeric().data.([]float64)
+	if !ok {
+		return nil, fmt.Errorf("Date_from_duration: cannot convert %T to a numeric duration", ser.data)
 	}
 
 	newdate := make([]time.Time, n)
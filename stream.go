@@ -0,0 +1,301 @@
+package datareader
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ChunkSource is implemented by readers (e.g. the SAS7BDAT and Stata
+// readers) that can deliver their rows in bounded-size pieces instead
+// of materializing the whole column at once.  ReadChunk returns up to
+// chunkRows rows starting at the source's current position, and
+// io.EOF once no rows remain.
+type ChunkSource interface {
+	ReadChunk(chunkRows int) (SeriesArray, error)
+}
+
+// ReusableChunkSource is an optional extension of ChunkSource for
+// readers that can fill a caller-owned SeriesArray in place instead of
+// allocating a new one per chunk.  ReadChunkInto writes up to
+// chunkRows rows into dst's existing per-column backing slices,
+// growing them only if dst is nil or too small, and returns the
+// (possibly reallocated) SeriesArray.  SeriesArrayIterator uses this
+// when present to satisfy ReuseBuffers.
+type ReusableChunkSource interface {
+	ChunkSource
+	ReadChunkInto(dst SeriesArray, chunkRows int) (SeriesArray, error)
+}
+
+// SeriesArrayIterator drives a ChunkSource, yielding one SeriesArray
+// per call to Next.  It is the pull-based counterpart to StreamRead.
+type SeriesArrayIterator struct {
+	src       ChunkSource
+	chunkRows int
+	reuse     bool
+	buf       SeriesArray
+}
+
+// NewSeriesArrayIterator returns an iterator that reads up to
+// chunkRows rows at a time from src.
+func NewSeriesArrayIterator(src ChunkSource, chunkRows int) *SeriesArrayIterator {
+	return &SeriesArrayIterator{src: src, chunkRows: chunkRows}
+}
+
+// ReuseBuffers controls whether successive calls to Next ask the
+// source to fill the previous chunk's backing slices in place instead
+// of allocating new ones.  This only takes effect if src implements
+// ReusableChunkSource; against a plain ChunkSource, Next always
+// allocates a fresh SeriesArray and ReuseBuffers has no effect.
+// Callers that opt in must finish using a chunk's data before calling
+// Next again.
+func (it *SeriesArrayIterator) ReuseBuffers(reuse bool) {
+	it.reuse = reuse
+}
+
+// Next returns the next chunk of up to chunkRows rows, or io.EOF when
+// the underlying ChunkSource is exhausted.
+func (it *SeriesArrayIterator) Next() (SeriesArray, error) {
+
+	if it.reuse {
+		if src, ok := it.src.(ReusableChunkSource); ok {
+			chunk, err := src.ReadChunkInto(it.buf, it.chunkRows)
+			if err != nil {
+				return nil, err
+			}
+			it.buf = chunk
+			return it.buf, nil
+		}
+	}
+
+	chunk, err := it.src.ReadChunk(it.chunkRows)
+	if err != nil {
+		return nil, err
+	}
+
+	it.buf = chunk
+	return it.buf, nil
+}
+
+// StreamRead drives src to completion on a background goroutine,
+// sending each chunk of at most chunkRows rows on the returned
+// channel.  The error channel receives at most one value: the error
+// that ended the stream, or nil on a clean io.EOF.  Both channels are
+// closed when the stream ends.
+func StreamRead(src ChunkSource, chunkRows int) (<-chan SeriesArray, <-chan error) {
+
+	out := make(chan SeriesArray)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errc)
+
+		it := NewSeriesArrayIterator(src, chunkRows)
+		for {
+			chunk, err := it.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+			out <- chunk
+		}
+	}()
+
+	return out, errc
+}
+
+// AllCloseInto is a chunk-friendly variant of AllClose: it compares
+// ser against other over the range [first, last) without allocating,
+// reusing the caller-provided tolerance.  It is otherwise identical to
+// AllClose, with indices relative to the start of the series rather
+// than the chunk.
+func (ser *Series) AllCloseInto(other *Series, tol float64, first, last int) (bool, int) {
+
+	switch u := ser.data.(type) {
+	default:
+		return false, -2
+	case []float64:
+		v, ok := other.data.([]float64)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []float32:
+		v, ok := other.data.([]float32)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []int64:
+		v, ok := other.data.([]int64)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []int32:
+		v, ok := other.data.([]int32)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []int16:
+		v, ok := other.data.([]int16)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []int8:
+		v, ok := other.data.([]int8)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []uint64:
+		v, ok := other.data.([]uint64)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []string:
+		v, ok := other.data.([]string)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	case []time.Time:
+		v, ok := other.data.([]time.Time)
+		if !ok {
+			return false, -2
+		}
+		return allCloseRange(u, v, ser.missing, other.missing, tol, first, last)
+	}
+}
+
+// UpcastNumericInto writes the float64 upcast of ser's numeric data
+// for the range [first, last) into out, rather than allocating a new
+// backing slice.  len(out) must be at least last-first.  Non-numeric
+// data is left untouched and UpcastNumericInto returns false.
+func (ser *Series) UpcastNumericInto(out []float64, first, last int) bool {
+
+	switch d := ser.data.(type) {
+	default:
+		return false
+	case []float64:
+		copy(out, d[first:last])
+	case []float32:
+		upcastRange(out, d, first, last)
+	case []int64:
+		upcastRange(out, d, first, last)
+	case []int32:
+		upcastRange(out, d, first, last)
+	case []int16:
+		upcastRange(out, d, first, last)
+	case []int8:
+		upcastRange(out, d, first, last)
+	case []uint64:
+		upcastRange(out, d, first, last)
+	}
+
+	return true
+}
+
+// ForceNumericInto writes the float64 parse of ser's string data for
+// the range [first, last) into out and the corresponding missingness
+// into outMiss, rather than allocating new backing slices.  len(out)
+// and len(outMiss) must be at least last-first.  Non-string data is
+// left untouched and ForceNumericInto returns false.
+func (ser *Series) ForceNumericInto(out []float64, outMiss []bool, first, last int) bool {
+
+	y, ok := ser.data.([]string)
+	if !ok {
+		return false
+	}
+
+	for i := first; i < last; i++ {
+		k := i - first
+		if (ser.missing != nil) && ser.missing[i] {
+			outMiss[k] = true
+			continue
+		}
+		v, err := strconv.ParseFloat(y[i], 64)
+		if err != nil {
+			outMiss[k] = true
+		} else {
+			out[k] = v
+			outMiss[k] = false
+		}
+	}
+
+	return true
+}
+
+// DateFromDurationInto writes the dates derived from ser's numeric
+// duration data for the range [first, last) into out, rather than
+// allocating a new backing slice.  scratch is a caller-owned working
+// slice used to hold the upcasted duration values; len(out) and
+// len(scratch) must both be at least last-first.
+func (ser *Series) DateFromDurationInto(out []time.Time, scratch []float64, base time.Time, units string, first, last int) error {
+
+	if units != "days" {
+		return fmt.Errorf("unknown time unit duration")
+	}
+
+	td := scratch[:last-first]
+	if !ser.UpcastNumericInto(td, first, last) {
+		return fmt.Errorf("DateFromDurationInto: cannot convert %T to a numeric duration", ser.data)
+	}
+
+	for i := first; i < last; i++ {
+		if (ser.missing == nil) || !ser.missing[i] {
+			out[i-first] = base.Add(time.Hour * time.Duration(24*td[i-first]))
+		}
+	}
+
+	return nil
+}
+
+// ToStringInto writes the string rendering of ser's data for the range
+// [first, last) into out, rather than allocating a new backing slice.
+// len(out) must be at least last-first.  A categorical Series renders
+// its labels; otherwise ToStringInto supports the same element types
+// as ToString, and leaves out untouched and returns false for any
+// other type.
+func (ser *Series) ToStringInto(out []string, first, last int) bool {
+
+	if ser.labels != nil {
+		codes, ok := ser.data.([]int64)
+		if !ok {
+			return false
+		}
+		for i := first; i < last; i++ {
+			k := i - first
+			if ser.missing != nil && ser.missing[i] {
+				out[k] = ""
+				continue
+			}
+			c := codes[i]
+			if lab, ok := ser.labels[c]; ok {
+				out[k] = lab
+			} else {
+				out[k] = fmt.Sprintf("%d", c)
+			}
+		}
+		return true
+	}
+
+	switch d := ser.data.(type) {
+	default:
+		return false
+	case []string:
+		copy(out, d[first:last])
+	case []float64:
+		writeStringRange(out, d, ser.missing, first, last)
+	}
+
+	return true
+}
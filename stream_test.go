@@ -0,0 +1,287 @@
+package datareader
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestAllCloseIntoMatchesAllCloseForEveryNumericType(t *testing.T) {
+
+	cases := []struct {
+		name string
+		a, b interface{}
+	}{
+		{"float64", []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{"float32", []float32{1, 2, 3}, []float32{1, 2, 3}},
+		{"int64", []int64{1, 2, 3}, []int64{1, 2, 3}},
+		{"int32", []int32{1, 2, 3}, []int32{1, 2, 3}},
+		{"int16", []int16{1, 2, 3}, []int16{1, 2, 3}},
+		{"int8", []int8{1, 2, 3}, []int8{1, 2, 3}},
+		{"uint64", []uint64{1, 2, 3}, []uint64{1, 2, 3}},
+		{"string", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := NewSeries(c.name, c.a, nil)
+			if err != nil {
+				t.Fatalf("NewSeries(a): %v", err)
+			}
+			b, err := NewSeries(c.name, c.b, nil)
+			if err != nil {
+				t.Fatalf("NewSeries(b): %v", err)
+			}
+
+			wantOK, wantI := a.AllClose(b, 0)
+			gotOK, gotI := a.AllCloseInto(b, 0, 0, a.Length())
+			if wantOK != gotOK || wantI != gotI {
+				t.Errorf("AllCloseInto(%s) = (%v, %d), want (%v, %d)", c.name, gotOK, gotI, wantOK, wantI)
+			}
+		})
+	}
+}
+
+func TestAllCloseIntoRejectsUnsupportedType(t *testing.T) {
+
+	a, err := NewSeries("x", []uint64{1, 2, 3}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+	b, err := NewSeries("x", []uint64{1, 2, 3}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+	a.data = struct{}{}
+
+	ok, code := a.AllCloseInto(b, 0, 0, 3)
+	if ok || code != -2 {
+		t.Fatalf("expected (false, -2) for an unsupported type, got (%v, %d)", ok, code)
+	}
+}
+
+func TestUpcastNumericIntoMatchesUpcastNumericForEveryNumericType(t *testing.T) {
+
+	cases := []struct {
+		name string
+		data interface{}
+	}{
+		{"float64", []float64{1, 2, 3, 4}},
+		{"float32", []float32{1, 2, 3, 4}},
+		{"int64", []int64{1, 2, 3, 4}},
+		{"int32", []int32{1, 2, 3, 4}},
+		{"int16", []int16{1, 2, 3, 4}},
+		{"int8", []int8{1, 2, 3, 4}},
+		{"uint64", []uint64{1, 2, 3, 4}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ser, err := NewSeries(c.name, c.data, nil)
+			if err != nil {
+				t.Fatalf("NewSeries: %v", err)
+			}
+
+			want := ser.UpcastNumeric().Data().([]float64)
+
+			got := make([]float64, ser.Length())
+			if !ser.UpcastNumericInto(got, 0, ser.Length()) {
+				t.Fatalf("UpcastNumericInto(%s) returned false for numeric data", c.name)
+			}
+
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("index %d: got %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUpcastNumericIntoRejectsUnsupportedType(t *testing.T) {
+
+	ser, err := NewSeries("x", []string{"a", "b", "c"}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+
+	out := make([]float64, ser.Length())
+	if ser.UpcastNumericInto(out, 0, ser.Length()) {
+		t.Fatalf("expected false for a non-numeric type")
+	}
+}
+
+func TestToStringIntoMatchesToString(t *testing.T) {
+
+	ser, err := NewSeries("x", []float64{1, 2, 3}, []bool{false, true, false})
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+
+	want := ser.ToString().Data().([]string)
+
+	got := make([]string, ser.Length())
+	if !ser.ToStringInto(got, 0, ser.Length()) {
+		t.Fatalf("ToStringInto returned false for float64 data")
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("index %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestToStringIntoRendersCategoricalLabels(t *testing.T) {
+
+	cs, err := NewCategoricalSeries("grp", []int64{5, 0, 5, 0}, nil, map[int64]string{
+		5: "high", 0: "low",
+	})
+	if err != nil {
+		t.Fatalf("NewCategoricalSeries: %v", err)
+	}
+
+	want := cs.Labels()
+
+	got := make([]string, cs.Length())
+	if !cs.ToStringInto(got, 0, cs.Length()) {
+		t.Fatalf("ToStringInto returned false for categorical data")
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("index %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestToStringIntoRejectsUnsupportedType(t *testing.T) {
+
+	ser, err := NewSeries("x", []int32{1, 2, 3}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+
+	out := make([]string, ser.Length())
+	if ser.ToStringInto(out, 0, ser.Length()) {
+		t.Fatalf("expected false for a type ToString does not support")
+	}
+}
+
+func TestDateFromDurationIntoMatchesDateFromDuration(t *testing.T) {
+
+	ser, err := NewSeries("x", []int32{0, 1, 2, 3}, []bool{false, true, false, false})
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+	base := time.Unix(0, 0).UTC()
+
+	want, err := ser.Date_from_duration(base, "days")
+	if err != nil {
+		t.Fatalf("Date_from_duration: %v", err)
+	}
+	wantDates := want.Data().([]time.Time)
+
+	got := make([]time.Time, ser.Length())
+	scratch := make([]float64, ser.Length())
+	if err := ser.DateFromDurationInto(got, scratch, base, "days", 0, ser.Length()); err != nil {
+		t.Fatalf("DateFromDurationInto: %v", err)
+	}
+
+	for i := range wantDates {
+		if want.missing[i] {
+			continue
+		}
+		if !got[i].Equal(wantDates[i]) {
+			t.Errorf("index %d: got %v, want %v", i, got[i], wantDates[i])
+		}
+	}
+}
+
+func TestDateFromDurationIntoRejectsUnsupportedType(t *testing.T) {
+
+	ser, err := NewSeries("x", []string{"a", "b"}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+
+	out := make([]time.Time, ser.Length())
+	scratch := make([]float64, ser.Length())
+	if err := ser.DateFromDurationInto(out, scratch, time.Unix(0, 0).UTC(), "days", 0, ser.Length()); err == nil {
+		t.Fatalf("expected an error for non-numeric data")
+	}
+}
+
+// fakeChunkSource hands out rows from a single backing SeriesArray in
+// fixed-size pieces, implementing both ChunkSource and
+// ReusableChunkSource.
+type fakeChunkSource struct {
+	data SeriesArray
+	pos  int
+}
+
+func (f *fakeChunkSource) ReadChunk(chunkRows int) (SeriesArray, error) {
+	if f.pos >= f.data[0].Length() {
+		return nil, io.EOF
+	}
+	last := f.pos + chunkRows
+	if last > f.data[0].Length() {
+		last = f.data[0].Length()
+	}
+
+	out := make(SeriesArray, len(f.data))
+	for j, s := range f.data {
+		sub, err := NewSeries(s.Name, sliceData(s.data, f.pos, last), sliceMissing(s.missing, f.pos, last))
+		if err != nil {
+			return nil, err
+		}
+		out[j] = sub
+	}
+	f.pos = last
+	return out, nil
+}
+
+func (f *fakeChunkSource) ReadChunkInto(dst SeriesArray, chunkRows int) (SeriesArray, error) {
+	chunk, err := f.ReadChunk(chunkRows)
+	if err != nil {
+		return nil, err
+	}
+	if dst == nil {
+		return chunk, nil
+	}
+	for j := range dst {
+		copy(dst[j].data.([]float64), chunk[j].data.([]float64))
+	}
+	return dst, nil
+}
+
+func TestSeriesArrayIteratorReusesBuffersViaReusableChunkSource(t *testing.T) {
+
+	full, err := NewSeries("x", []float64{1, 2, 3, 4, 5}, nil)
+	if err != nil {
+		t.Fatalf("NewSeries: %v", err)
+	}
+	src := &fakeChunkSource{data: SeriesArray{full}}
+
+	it := NewSeriesArrayIterator(src, 2)
+	it.ReuseBuffers(true)
+
+	first, err := it.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	firstData := first[0].data.([]float64)
+
+	second, err := it.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	secondData := second[0].data.([]float64)
+
+	if &firstData[0] != &secondData[0] {
+		t.Fatalf("expected ReuseBuffers to reuse the backing array across chunks")
+	}
+	if secondData[0] != 3 || secondData[1] != 4 {
+		t.Fatalf("got %v, want second chunk [3 4]", secondData)
+	}
+}
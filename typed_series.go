@@ -0,0 +1,203 @@
+package datareader
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Numeric is the set of element types that TypedSeries accepts in
+// addition to string and time.Time.  It mirrors the numeric cases
+// handled by the type switches in Series.
+type Numeric interface {
+	~float64 | ~float32 | ~int64 | ~int32 | ~int16 | ~int8 | ~uint64
+}
+
+// TypedSeries is a generic counterpart to Series.  It stores its data
+// as a concrete []T slice instead of interface{}, so callers that know
+// their element type at compile time can avoid the type switches that
+// Series requires.  TypedSeries is produced from a Series with
+// AsTyped, and converted back with FromTyped.
+//
+// This file also holds the generic algorithms (allCloseRange,
+// writeRange, upcastRange, toStringRange) that Series builds on, so
+// that a Series method's type switch picks the element type once and
+// delegates the actual logic to a single generic implementation
+// instead of repeating it per case.  Of these, TypedSeries itself
+// uses only allCloseRange (in AllClose).
+type TypedSeries[T Numeric | string | time.Time] struct {
+
+	// A name describing what is in this series.
+	Name string
+
+	data []T
+
+	// Indicators that data values are missing.  If nil, there are
+	// no missing values.
+	missing []bool
+}
+
+// NewTypedSeries returns a new TypedSeries with the given name and
+// data contents.
+func NewTypedSeries[T Numeric | string | time.Time](name string, data []T, missing []bool) *TypedSeries[T] {
+	return &TypedSeries[T]{
+		Name:    name,
+		data:    data,
+		missing: missing,
+	}
+}
+
+// Data returns the data component of the TypedSeries.
+func (ts *TypedSeries[T]) Data() []T {
+	return ts.data
+}
+
+// Missing returns the array of missing value indicators.
+func (ts *TypedSeries[T]) Missing() []bool {
+	return ts.missing
+}
+
+// Length returns the number of elements in a TypedSeries.
+func (ts *TypedSeries[T]) Length() int {
+	return len(ts.data)
+}
+
+// Map applies f to every non-missing value of the TypedSeries,
+// in place.
+func (ts *TypedSeries[T]) Map(f func(T) T) {
+	for i := range ts.data {
+		if (ts.missing == nil) || !ts.missing[i] {
+			ts.data[i] = f(ts.data[i])
+		}
+	}
+}
+
+// closeT reports whether a and b are within tol of each other.  For
+// the string and time.Time element types, tol is ignored and the
+// values must be exactly equal.
+func closeT[T Numeric | string | time.Time](a, b T, tol float64) bool {
+	switch x := any(a).(type) {
+	case float64:
+		return math.Abs(x-any(b).(float64)) <= tol
+	case float32:
+		return math.Abs(float64(x-any(b).(float32))) <= tol
+	case time.Time:
+		return x.Equal(any(b).(time.Time))
+	case string:
+		return x == any(b).(string)
+	default:
+		// Remaining Numeric cases (integer types) compare exactly.
+		return any(a) == any(b)
+	}
+}
+
+// allCloseRange compares u against v over [first, last), honoring each
+// side's missingness mask and tol.  It returns false, i at the first
+// index where the two series are inconsistently missing or not
+// within tol of each other.  This is the single implementation
+// shared by Series.AllClose, Series.AllCloseInto, and
+// TypedSeries.AllClose, replacing what used to be a full type-switch
+// arm repeated in each of those methods.
+func allCloseRange[T Numeric | string | time.Time](u, v []T, umiss, vmiss []bool, tol float64, first, last int) (bool, int) {
+	for i := first; i < last; i++ {
+		f1 := (umiss == nil) || !umiss[i]
+		f2 := (vmiss == nil) || !vmiss[i]
+		if f1 != f2 {
+			return false, i
+		}
+		if f1 && !closeT(u[i], v[i], tol) {
+			return false, i
+		}
+	}
+	return true, 0
+}
+
+// AllClose returns true, 0 if the TypedSeries is within tol of the
+// other TypedSeries.  If the TypedSeries have different lengths,
+// AllClose returns false, -1.  If the TypedSeries have the same
+// length but are not equal, AllClose returns false, j, where j is the
+// index of the first position where the two series differ.
+func (ts *TypedSeries[T]) AllClose(other *TypedSeries[T], tol float64) (bool, int) {
+
+	if len(ts.data) != len(other.data) {
+		return false, -1
+	}
+
+	return allCloseRange(ts.data, other.data, ts.missing, other.missing, tol, 0, len(ts.data))
+}
+
+// AllEqual is equivalent to AllClose with tol=0.
+func (ts *TypedSeries[T]) AllEqual(other *TypedSeries[T]) (bool, int) {
+	return ts.AllClose(other, 0.0)
+}
+
+// AsTyped converts a Series to a TypedSeries[T], returning an error
+// if the Series does not hold a []T.  Callers that know the element
+// type of a Series (e.g. readers that just constructed it) can use
+// AsTyped to avoid repeating the type switches that Series.Data
+// otherwise forces on them.
+func AsTyped[T Numeric | string | time.Time](ser *Series) (*TypedSeries[T], error) {
+
+	data, ok := ser.data.([]T)
+	if !ok {
+		return nil, fmt.Errorf("Series %s does not hold the requested type: got %T", ser.Name, ser.data)
+	}
+
+	return &TypedSeries[T]{
+		Name:    ser.Name,
+		data:    data,
+		missing: ser.missing,
+	}, nil
+}
+
+// FromTyped converts a TypedSeries[T] back into the legacy Series
+// representation, so that existing call sites built around Series
+// keep working unchanged.
+func FromTyped[T Numeric | string | time.Time](ts *TypedSeries[T]) (*Series, error) {
+	return NewSeries(ts.Name, ts.data, ts.missing)
+}
+
+// writeRange writes the subinterval [first, last) of data to w, one
+// value per line, using missing to blank out missing entries.  It is
+// the single implementation shared by every element-type arm of
+// Series.WriteRange.
+func writeRange[T any](w io.Writer, data []T, missing []bool, first, last int) {
+	for j := first; j < last; j++ {
+		if (missing == nil) || !missing[j] {
+			io.WriteString(w, fmt.Sprintf("%d:  %v\n", j, data[j]))
+		} else {
+			io.WriteString(w, fmt.Sprintf("%d:\n", j))
+		}
+	}
+}
+
+// upcastRange writes the float64 upcast of data[first:last] into
+// out[0:last-first].  It is the single implementation shared by
+// Series.UpcastNumeric and Series.UpcastNumericInto.
+func upcastRange[T Numeric](out []float64, data []T, first, last int) {
+	for i := first; i < last; i++ {
+		out[i-first] = float64(data[i])
+	}
+}
+
+// writeStringRange renders data[first:last] with fmt's default verb
+// into out[0:last-first], leaving missing entries as "".  It is the
+// single implementation shared by toStringRange and
+// Series.ToStringInto.
+func writeStringRange[T Numeric](out []string, data []T, missing []bool, first, last int) {
+	for i := first; i < last; i++ {
+		if (missing == nil) || !missing[i] {
+			out[i-first] = fmt.Sprintf("%v", data[i])
+		}
+	}
+}
+
+// toStringRange renders data[first:last] with fmt's default verb,
+// leaving missing entries as "".  It is the single implementation
+// shared by the numeric element-type arms of Series.ToString.
+func toStringRange[T Numeric](data []T, missing []bool, first, last int) []string {
+	out := make([]string, last-first)
+	writeStringRange(out, data, missing, first, last)
+	return out
+}